@@ -2,13 +2,21 @@
 package main
 
 import (
+	"container/heap"
+	"encoding/gob"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/williamkoller/go-native-cache/metrics"
 )
 
 // CacheItem representa um item no cache
@@ -21,10 +29,31 @@ type CacheItem struct {
 // Cache é um cache thread-safe com limpeza automática
 type Cache struct {
 	sync.RWMutex
-	items         map[string]CacheItem
-	defaultTTL    time.Duration
-	cleanupTicker *time.Ticker
-	stats         CacheStats
+	store       Store
+	defaultTTL  time.Duration
+	cleanupTick time.Duration
+	stats       CacheStats
+	done        chan struct{}
+	stopOnce    sync.Once
+
+	// expHeap/expIndex mantêm uma fila de prioridade das expirações, para
+	// que a limpeza não precise varrer todo o mapa a cada execução.
+	expHeap  expirationHeap
+	expIndex map[string]*expirationEntry
+	// wake acorda a goroutine de limpeza assim que uma expiração mais
+	// próxima que a atualmente agendada é inserida.
+	wake chan struct{}
+
+	// sfGroup colapsa chamadas concorrentes de loader para a mesma chave
+	// em GetOrLoad, evitando cache stampede.
+	sfGroup singleflight.Group
+
+	// name rotula as métricas Prometheus deste cache. Métricas só são
+	// ativadas explicitamente via EnableMetrics (ver metrics.go), nunca
+	// automaticamente em NewCache: ShardedCache cria vários Cache no mesmo
+	// processo, e todos rotulariam suas séries com o mesmo name "default".
+	name    string
+	metrics *metrics.Collector
 }
 
 // CacheStats mantém estatísticas do cache
@@ -35,6 +64,11 @@ type CacheStats struct {
 	ItemsExpired int64 `json:"items_expired"`
 	ItemsDeleted int64 `json:"items_deleted"`
 	CleanupRuns  int64 `json:"cleanup_runs"`
+
+	// bytesInUse estima, em bytes, o espaço ocupado pelos itens atualmente
+	// no cache. Não é parte da API pública de estatísticas (CacheStatsData);
+	// existe apenas para alimentar a métrica Prometheus cache_bytes.
+	bytesInUse int64
 }
 
 // CacheStatsData contém apenas os dados estatísticos sem o mutex
@@ -46,21 +80,22 @@ type CacheStatsData struct {
 	CleanupRuns  int64 `json:"cleanup_runs"`
 }
 
-// NewCache cria uma nova instância do cache
-func NewCache(defaultTTL time.Duration, cleanupInterval time.Duration) *Cache {
+// NewCache cria uma nova instância do cache sobre o backend store. Use
+// newStore para escolher o backend (memory, badger ou redis) a partir do
+// flag -backend do servidor HTTP.
+func NewCache(defaultTTL time.Duration, cleanupInterval time.Duration, store Store) *Cache {
 	c := &Cache{
-		items:         make(map[string]CacheItem),
-		defaultTTL:    defaultTTL,
-		cleanupTicker: time.NewTicker(cleanupInterval),
-		stats:         CacheStats{},
+		store:       store,
+		defaultTTL:  defaultTTL,
+		cleanupTick: cleanupInterval,
+		stats:       CacheStats{},
+		expIndex:    make(map[string]*expirationEntry),
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
 	}
 
-	// Goroutine de limpeza automática
-	go func() {
-		for range c.cleanupTicker.C {
-			c.cleanup()
-		}
-	}()
+	// Goroutine de limpeza automática, orientada pelo heap de expiração
+	go c.startCleanup()
 
 	log.Printf("Cache inicializado - TTL padrão: %v, Limpeza a cada: %v", defaultTTL, cleanupInterval)
 	return c
@@ -69,32 +104,95 @@ func NewCache(defaultTTL time.Duration, cleanupInterval time.Duration) *Cache {
 // Set adiciona um item ao cache
 func (c *Cache) Set(key string, value interface{}, ttl ...time.Duration) {
 	c.Lock()
-	defer c.Unlock()
 
 	duration := c.defaultTTL
 	if len(ttl) > 0 {
 		duration = ttl[0]
 	}
 
-	c.items[key] = CacheItem{
+	expiresAt := time.Now().Add(duration)
+	if c.metrics != nil {
+		// O Get extra só é pago quando métricas estão ativas: sem ele, Set
+		// faria uma leitura a mais no backend (Badger/Redis) a cada
+		// gravação, só para alimentar um gauge que ninguém está coletando.
+		old, hadOld := c.store.Get(key)
+		newSize := approxSize(value)
+		if hadOld {
+			c.stats.addBytes(newSize - approxSize(old.Value))
+		} else {
+			c.stats.addBytes(newSize)
+		}
+	}
+	c.store.Set(key, CacheItem{
 		Value:     value,
-		ExpiresAt: time.Now().Add(duration),
+		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
+	})
+
+	isEarliest := c.scheduleExpirationLocked(key, expiresAt)
+	c.reportGaugesLocked()
+	c.Unlock()
+
+	if c.metrics != nil {
+		c.metrics.ObserveSet(c.metricsName())
+	}
+	if isEarliest {
+		c.wakeCleanup()
 	}
 
 	log.Printf("Item adicionado ao cache: %s (TTL: %v)", key, duration)
 }
 
+// scheduleExpirationLocked insere ou atualiza a entrada de key no heap de
+// expiração. Deve ser chamado com o lock de c já travado. Retorna true
+// quando a expiração passou a ser a mais próxima do heap, indicando que a
+// goroutine de limpeza precisa ser acordada para reagendar sua espera.
+func (c *Cache) scheduleExpirationLocked(key string, expiresAt time.Time) bool {
+	if entry, ok := c.expIndex[key]; ok {
+		entry.expiresAt = expiresAt
+		heap.Fix(&c.expHeap, entry.index)
+	} else {
+		entry = &expirationEntry{key: key, expiresAt: expiresAt}
+		heap.Push(&c.expHeap, entry)
+		c.expIndex[key] = entry
+	}
+
+	return c.expHeap[0].key == key
+}
+
+// unscheduleExpirationLocked remove a entrada de key do heap de expiração,
+// se houver uma. Deve ser chamado com o lock de c já travado.
+func (c *Cache) unscheduleExpirationLocked(key string) {
+	if entry, ok := c.expIndex[key]; ok {
+		heap.Remove(&c.expHeap, entry.index)
+		delete(c.expIndex, key)
+	}
+}
+
+// wakeCleanup notifica a goroutine de limpeza de que uma expiração mais
+// próxima foi agendada, sem bloquear caso ela já esteja acordada.
+func (c *Cache) wakeCleanup() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
 // Get recupera um item do cache
 func (c *Cache) Get(key string) (interface{}, bool) {
+	start := time.Now()
 	c.RLock()
 	defer c.RUnlock()
 
-	item, exists := c.items[key]
+	item, exists := c.store.Get(key)
 	if !exists {
 		c.stats.Lock()
 		c.stats.Misses++
 		c.stats.Unlock()
+		if c.metrics != nil {
+			c.metrics.ObserveMiss(c.metricsName())
+			c.metrics.ObserveGetDuration(c.metricsName(), time.Since(start).Seconds())
+		}
 		return nil, false
 	}
 
@@ -102,26 +200,69 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 		c.stats.Lock()
 		c.stats.Misses++
 		c.stats.Unlock()
+		if c.metrics != nil {
+			c.metrics.ObserveMiss(c.metricsName())
+			c.metrics.ObserveGetDuration(c.metricsName(), time.Since(start).Seconds())
+		}
 		return nil, false
 	}
 
 	c.stats.Lock()
 	c.stats.Hits++
 	c.stats.Unlock()
+	if c.metrics != nil {
+		c.metrics.ObserveHit(c.metricsName())
+		c.metrics.ObserveGetDuration(c.metricsName(), time.Since(start).Seconds())
+	}
 
 	return item.Value, true
 }
 
+// Keys retorna todas as chaves armazenadas no cache
+func (c *Cache) Keys() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	keys := make([]string, 0, c.store.Len())
+	c.store.Iterate(func(key string, _ CacheItem) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Has verifica se uma chave existe no cache
+func (c *Cache) Has(key string) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	item, exists := c.store.Get(key)
+	if !exists {
+		return false
+	}
+
+	return !time.Now().After(item.ExpiresAt)
+}
+
 // Delete remove um item do cache
 func (c *Cache) Delete(key string) bool {
 	c.Lock()
 	defer c.Unlock()
 
-	if _, exists := c.items[key]; exists {
-		delete(c.items, key)
+	if item, exists := c.store.Get(key); exists {
+		c.store.Delete(key)
+		c.unscheduleExpirationLocked(key)
+		if c.metrics != nil {
+			c.stats.addBytes(-approxSize(item.Value))
+		}
 		c.stats.Lock()
 		c.stats.ItemsDeleted++
 		c.stats.Unlock()
+		c.reportGaugesLocked()
+
+		if c.metrics != nil {
+			c.metrics.ObserveDelete(c.metricsName())
+		}
 		log.Printf("Item removido do cache: %s", key)
 		return true
 	}
@@ -129,21 +270,64 @@ func (c *Cache) Delete(key string) bool {
 	return false
 }
 
-// cleanup remove itens expirados do cache
+// startCleanup dorme até a expiração mais próxima conhecida pelo heap,
+// disparando a limpeza o quanto antes em vez de varrer o mapa em um
+// intervalo fixo. Um Set com uma expiração mais próxima reinicia a espera
+// através do canal wake.
+func (c *Cache) startCleanup() {
+	for {
+		c.RLock()
+		delay := c.nextCleanupDelayLocked()
+		c.RUnlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+			c.cleanup()
+		case <-c.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// nextCleanupDelayLocked calcula quanto tempo falta até a próxima
+// expiração conhecida. Deve ser chamado com o lock de c já travado (leitura
+// ou escrita). Na ausência de itens, cai de volta para cleanupTick.
+func (c *Cache) nextCleanupDelayLocked() time.Duration {
+	if len(c.expHeap) == 0 {
+		return c.cleanupTick
+	}
+	if delay := time.Until(c.expHeap[0].expiresAt); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// cleanup remove apenas os itens cujo prazo já venceu, retirando-os do
+// topo do heap de expiração em vez de varrer o mapa inteiro.
 func (c *Cache) cleanup() {
 	c.Lock()
-	defer c.Unlock()
 
-	now := time.Now()
 	expired := 0
-
-	for k, v := range c.items {
-		if now.After(v.ExpiresAt) {
-			delete(c.items, k)
-			expired++
+	now := time.Now()
+	for len(c.expHeap) > 0 && !c.expHeap[0].expiresAt.After(now) {
+		entry := heap.Pop(&c.expHeap).(*expirationEntry)
+		delete(c.expIndex, entry.key)
+		if c.metrics != nil {
+			if item, exists := c.store.Get(entry.key); exists {
+				c.stats.addBytes(-approxSize(item.Value))
+			}
 		}
+		c.store.Delete(entry.key)
+		expired++
 	}
 
+	c.reportGaugesLocked()
+	c.Unlock()
+
 	c.stats.Lock()
 	c.stats.ItemsExpired += int64(expired)
 	c.stats.CleanupRuns++
@@ -168,13 +352,51 @@ func (c *Cache) GetStats() CacheStatsData {
 	}
 }
 
+// GetOrLoad recupera key do cache; em caso de miss, calcula o valor com
+// loader e o grava com ttl. Chamadas concorrentes para a mesma key colapsam
+// em uma única execução de loader via singleflight, evitando que uma
+// rajada de misses simultâneos recalcule o mesmo valor N vezes (cache
+// stampede). O bool retornado indica se o valor já estava em cache (hit)
+// ou veio de loader (miss); Get é chamado exatamente uma vez, para que esse
+// resultado reflita de fato o que foi servido.
+func (c *Cache) GetOrLoad(key string, ttl time.Duration, loader func() (interface{}, error)) (interface{}, bool, error) {
+	if value, exists := c.Get(key); exists {
+		return value, true, nil
+	}
+
+	value, err, _ := c.sfGroup.Do(key, func() (interface{}, error) {
+		value, err := loader()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Set(key, value, ttl)
+		return value, nil
+	})
+
+	return value, false, err
+}
+
 // Clear limpa todo o cache
 func (c *Cache) Clear() {
 	c.Lock()
 	defer c.Unlock()
 
-	itemCount := len(c.items)
-	c.items = make(map[string]CacheItem)
+	itemCount := c.store.Len()
+	keys := make([]string, 0, itemCount)
+	c.store.Iterate(func(key string, _ CacheItem) bool {
+		keys = append(keys, key)
+		return true
+	})
+	for _, key := range keys {
+		c.store.Delete(key)
+	}
+	c.expHeap = nil
+	c.expIndex = make(map[string]*expirationEntry)
+	c.stats.Lock()
+	c.stats.bytesInUse = 0
+	c.stats.Unlock()
+	c.reportGaugesLocked()
 
 	log.Printf("Cache limpo: %d itens removidos", itemCount)
 }
@@ -183,12 +405,17 @@ func (c *Cache) Clear() {
 func (c *Cache) Size() int {
 	c.RLock()
 	defer c.RUnlock()
-	return len(c.items)
+	return c.store.Len()
 }
 
 // Stop para a goroutine de limpeza
 func (c *Cache) Stop() {
-	c.cleanupTicker.Stop()
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+	if err := c.store.Close(); err != nil {
+		log.Printf("Erro ao fechar o backend do cache: %v", err)
+	}
 	log.Println("Cache parado")
 }
 
@@ -233,30 +460,28 @@ func getUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	cacheKey := fmt.Sprintf("user:%d", userID)
 
-	// Tenta buscar no cache primeiro
-	if cachedData, found := cache.Get(cacheKey); found {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("X-Cache", "HIT")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"data":   cachedData,
-			"cached": true,
-		})
-		log.Printf("Cache HIT para usuário %d", userID)
+	// Chamadas concorrentes em miss colapsam em uma única simulateDBQuery
+	// via singleflight, evitando cache stampede. GetOrLoad chama Get uma
+	// única vez e reporta se serviu um hit ou um miss.
+	data, hit, err := cache.GetOrLoad(cacheKey, 30*time.Second, func() (interface{}, error) {
+		log.Printf("Cache MISS para usuário %d - buscando no banco", userID)
+		return simulateDBQuery(userID), nil
+	})
+	if err != nil {
+		http.Error(w, "Erro ao buscar dados do usuário", http.StatusInternalServerError)
 		return
 	}
 
-	// Cache miss - busca no "banco de dados"
-	log.Printf("Cache MISS para usuário %d - buscando no banco", userID)
-	userData := simulateDBQuery(userID)
-
-	// Armazena no cache por 30 segundos
-	cache.Set(cacheKey, userData, 30*time.Second)
-
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Cache", "MISS")
+	if hit {
+		w.Header().Set("X-Cache", "HIT")
+		log.Printf("Cache HIT para usuário %d", userID)
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"data":   userData,
-		"cached": false,
+		"data":   data,
+		"cached": hit,
 	})
 }
 
@@ -398,14 +623,33 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	backend := flag.String("backend", "memory", "backend de armazenamento do cache: memory, badger ou redis")
+	dsn := flag.String("backend-dsn", "", "diretório (badger) ou URL de conexão (redis); ignorado para memory")
+	flag.Parse()
+
+	store, err := newStore(*backend, *dsn)
+	if err != nil {
+		log.Fatalf("Erro ao inicializar o backend %q: %v", *backend, err)
+	}
+
 	// Inicializa o cache
 	// TTL padrão: 60 segundos
 	// Limpeza automática: a cada 10 segundos
-	cache = NewCache(60*time.Second, 10*time.Second)
+	// approxSize codifica o valor em gob para estimar seu tamanho (métrica
+	// cache_bytes); tipos guardados como interface{} em CacheItem.Value
+	// precisam estar registrados, senão a codificação falha e o tamanho
+	// estimado cai silenciosamente para zero.
+	gob.Register(UserData{})
+	gob.Register(map[string]interface{}{})
+
+	cache = NewCache(60*time.Second, 10*time.Second, store)
 	defer cache.Stop()
 
+	cache.EnableMetrics("default", nil)
+
 	// Configura rotas
 	http.HandleFunc("/", homeHandler)
+	http.Handle("/metrics", promhttp.Handler())
 	http.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -433,25 +677,30 @@ func main() {
 			"ativo": true,
 		}, 90*time.Second)
 
-		// Simula alta concorrência
+		// Simula alta concorrência com um ShardedCache: 100 goroutines batendo
+		// Set/Get simultaneamente espalham as chaves por vários shards, em
+		// vez de competir todas pelo único lock de um Cache comum.
+		sharded := NewShardedCache(15*time.Second, 10*time.Second, 16)
+		defer sharded.Close()
+
 		var wg sync.WaitGroup
 		for i := 0; i < 100; i++ {
 			wg.Add(1)
 			go func(id int) {
 				defer wg.Done()
 				key := fmt.Sprintf("concurrent:%d", id)
-				cache.Set(key, fmt.Sprintf("valor_%d", id), 15*time.Second)
+				sharded.Set(key, fmt.Sprintf("valor_%d", id))
 
 				// Simula leituras
 				for j := 0; j < 5; j++ {
-					cache.Get(key)
+					sharded.Get(key)
 					time.Sleep(time.Millisecond * 10)
 				}
 			}(i)
 		}
 		wg.Wait()
 
-		log.Println("Dados de exemplo adicionados!")
+		log.Printf("Dados de exemplo adicionados! ShardedCache ficou com %d itens.", sharded.Size())
 	}()
 
 	port := ":8080"
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/williamkoller/go-native-cache/metrics"
+)
+
+// EnableMetrics liga as métricas Prometheus deste cache, registrando-as em
+// reg (ou no DefaultRegisterer, se reg for nil) através do mesmo
+// metrics.Collector usado pelo NativeCache do pacote raiz, em vez de um
+// coletor próprio. name rotula as séries deste cache.
+//
+// Chamar EnableMetrics várias vezes com o mesmo reg é seguro: metrics.New
+// reusa o Collector já registrado nesse Registry em vez de tentar registrar
+// as mesmas métricas de novo. Ainda assim, EnableMetrics nunca é chamado
+// automaticamente em NewCache -- cada shard de ShardedCache teria o mesmo
+// name "default" por padrão, e expor métricas por shard não foi pedido.
+func (c *Cache) EnableMetrics(name string, reg *prometheus.Registry) {
+	c.name = name
+	c.metrics = metrics.New(reg)
+}
+
+// metricsName retorna o nome usado para rotular as métricas deste cache.
+func (c *Cache) metricsName() string {
+	if c.name == "" {
+		return "default"
+	}
+	return c.name
+}
+
+// approxSize estima o tamanho de value, em bytes, pela sua codificação gob,
+// usado para alimentar a métrica cache_bytes. Valores que não podem ser
+// codificados (ex.: funções, canais) contam como tamanho zero.
+func approxSize(value interface{}) int64 {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return 0
+	}
+	return int64(buf.Len())
+}
+
+// addBytes soma delta ao total estimado de bytes em uso.
+func (s *CacheStats) addBytes(delta int64) {
+	s.Lock()
+	s.bytesInUse += delta
+	s.Unlock()
+}
+
+// currentBytes retorna o total estimado de bytes em uso.
+func (s *CacheStats) currentBytes() int64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.bytesInUse
+}
+
+// reportGaugesLocked atualiza as métricas Prometheus cache_items/cache_bytes
+// com o estado atual do cache. Deve ser chamado com c já travado (leitura ou
+// escrita); é um no-op quando EnableMetrics não foi chamado.
+func (c *Cache) reportGaugesLocked() {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.SetItems(c.metricsName(), float64(c.store.Len()))
+	c.metrics.SetBytes(c.metricsName(), float64(c.stats.currentBytes()))
+}
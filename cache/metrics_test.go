@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestByteAccountingTracksSetAndDelete cobre a regressão em que o
+// demo HTTP expunha um cacheCollector próprio, sem cache_sets_total,
+// cache_evictions_total, cache_bytes nem cache_get_duration_seconds. Ao
+// reusar metrics.Collector, cache_bytes precisa refletir o tamanho real dos
+// itens conforme eles são gravados e removidos -- mas só quando
+// EnableMetrics foi chamado, para não pagar um Get extra no backend em todo
+// Set de quem nunca ligou as métricas.
+func TestByteAccountingTracksSetAndDelete(t *testing.T) {
+	c := NewCache(time.Minute, time.Minute, newMemoryStore())
+	defer c.Stop()
+	c.EnableMetrics("test", prometheus.NewRegistry())
+
+	c.Set("a", "valor")
+	if got := c.stats.currentBytes(); got == 0 {
+		t.Fatal("esperava bytesInUse maior que zero após Set")
+	}
+
+	c.Delete("a")
+	if got := c.stats.currentBytes(); got != 0 {
+		t.Fatalf("esperava bytesInUse zerado após remover o único item, got %d", got)
+	}
+}
+
+// TestMetricsNameDefaultsWhenUnset garante que metricsName cai para
+// "default" quando EnableMetrics não foi chamado com um nome.
+func TestMetricsNameDefaultsWhenUnset(t *testing.T) {
+	c := NewCache(time.Minute, time.Minute, newMemoryStore())
+	defer c.Stop()
+
+	if got := c.metricsName(); got != "default" {
+		t.Fatalf("esperava \"default\", got %q", got)
+	}
+}
+
+// TestSetSkipsByteAccountingWithoutMetrics garante que, sem EnableMetrics,
+// Set não mantém bytesInUse (e portanto não paga o Get extra no backend que
+// só serve para alimentar a métrica cache_bytes).
+func TestSetSkipsByteAccountingWithoutMetrics(t *testing.T) {
+	c := NewCache(time.Minute, time.Minute, newMemoryStore())
+	defer c.Stop()
+
+	c.Set("a", "valor")
+	if got := c.stats.currentBytes(); got != 0 {
+		t.Fatalf("esperava bytesInUse 0 sem EnableMetrics, got %d", got)
+	}
+}
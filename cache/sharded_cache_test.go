@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestShardedCacheGetStatsAggregatesAcrossShards cobre a regressão em que
+// ShardedCache não implementava GetStats (nem Delete/Has/Keys/Clear),
+// quebrando a paridade de API exigida com Cache.
+func TestShardedCacheGetStatsAggregatesAcrossShards(t *testing.T) {
+	sc := &ShardedCache{
+		shards: []*Cache{
+			NewCache(time.Minute, time.Minute, newMemoryStore()),
+			NewCache(time.Minute, time.Minute, newMemoryStore()),
+		},
+		mask: 1,
+	}
+	defer sc.Close()
+
+	for _, shard := range sc.shards {
+		shard.Set("a", "1")
+		shard.Get("a")
+		shard.Delete("a")
+	}
+
+	stats := sc.GetStats()
+	if stats.Hits != 2 {
+		t.Fatalf("esperava 2 hits agregados, got %d", stats.Hits)
+	}
+	if stats.ItemsDeleted != 2 {
+		t.Fatalf("esperava 2 items deleted agregados, got %d", stats.ItemsDeleted)
+	}
+}
+
+// TestShardedCacheDeleteHasKeysClear cobre Delete/Has/Keys/Clear na API
+// pública de ShardedCache, que devem se comportar como as de Cache.
+func TestShardedCacheDeleteHasKeysClear(t *testing.T) {
+	sc := NewShardedCache(time.Minute, time.Minute, 4)
+	defer sc.Close()
+
+	sc.Set("a", "1")
+	sc.Set("b", "2")
+
+	if !sc.Has("a") {
+		t.Fatal("esperava Has(\"a\") == true")
+	}
+
+	keys := sc.Keys()
+	if len(keys) != 2 {
+		t.Fatalf("esperava 2 chaves, got %d", len(keys))
+	}
+
+	if !sc.Delete("a") {
+		t.Fatal("esperava Delete(\"a\") == true")
+	}
+	if sc.Has("a") {
+		t.Fatal("esperava Has(\"a\") == false após Delete")
+	}
+
+	sc.Clear()
+	if sc.Size() != 0 {
+		t.Fatalf("esperava cache vazio após Clear, got %d itens", sc.Size())
+	}
+}
+
+// concurrentReadWrite escreve e lê numGoroutines chaves distintas n vezes
+// cada, usada tanto pelo benchmark sharded quanto pelo unsharded para que a
+// única diferença entre os dois seja o cache usado.
+func concurrentReadWrite(b *testing.B, set func(key, value string), get func(key string)) {
+	const numGoroutines = 64
+	var wg sync.WaitGroup
+
+	for i := 0; i < b.N; i++ {
+		wg.Add(numGoroutines)
+		for g := 0; g < numGoroutines; g++ {
+			go func(g int) {
+				defer wg.Done()
+				key := fmt.Sprintf("key:%d", g)
+				set(key, "valor")
+				get(key)
+			}(g)
+		}
+		wg.Wait()
+	}
+}
+
+// BenchmarkShardedCacheConcurrent mede o throughput de Set/Get concorrentes
+// num ShardedCache, para comparar com BenchmarkUnshardedCacheConcurrent.
+func BenchmarkShardedCacheConcurrent(b *testing.B) {
+	sc := NewShardedCache(time.Minute, time.Minute, 64)
+	defer sc.Close()
+
+	b.ResetTimer()
+	concurrentReadWrite(b, func(key, value string) { sc.Set(key, value) }, func(key string) { sc.Get(key) })
+}
+
+// BenchmarkUnshardedCacheConcurrent mede o mesmo cenário num único Cache,
+// para comparar com BenchmarkShardedCacheConcurrent.
+func BenchmarkUnshardedCacheConcurrent(b *testing.B) {
+	cache := NewCache(time.Minute, time.Minute, newMemoryStore())
+	defer cache.Stop()
+
+	b.ResetTimer()
+	concurrentReadWrite(b, func(key, value string) { cache.Set(key, value) }, func(key string) { cache.Get(key) })
+}
@@ -0,0 +1,73 @@
+package main
+
+import "fmt"
+
+// Store é o backend de persistência usado pelo Cache. O Cache continua
+// responsável pela expiração (heap) e estatísticas; o Store só guarda e
+// recupera os itens, o que permite trocar o mapa em memória por um backend
+// em disco ou remoto sem mexer no resto do cache.
+type Store interface {
+	Get(key string) (CacheItem, bool)
+	Set(key string, item CacheItem)
+	Delete(key string)
+	Iterate(fn func(key string, item CacheItem) bool)
+	Len() int
+	Close() error
+}
+
+// memoryStore é o backend padrão: um mapa em memória. Não tem lock próprio,
+// pois é sempre usado sob o lock do Cache.
+type memoryStore struct {
+	items map[string]CacheItem
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{items: make(map[string]CacheItem)}
+}
+
+func (s *memoryStore) Get(key string) (CacheItem, bool) {
+	item, ok := s.items[key]
+	return item, ok
+}
+
+func (s *memoryStore) Set(key string, item CacheItem) {
+	s.items[key] = item
+}
+
+func (s *memoryStore) Delete(key string) {
+	delete(s.items, key)
+}
+
+func (s *memoryStore) Iterate(fn func(key string, item CacheItem) bool) {
+	for key, item := range s.items {
+		if !fn(key, item) {
+			return
+		}
+	}
+}
+
+func (s *memoryStore) Len() int {
+	return len(s.items)
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+var _ Store = (*memoryStore)(nil)
+
+// newStore constrói o Store indicado pelo flag -backend do servidor HTTP.
+// backend deve ser "memory", "badger" ou "redis"; dsn é o diretório (Badger)
+// ou a URL de conexão (Redis), ignorado para "memory".
+func newStore(backend, dsn string) (Store, error) {
+	switch backend {
+	case "", "memory":
+		return newMemoryStore(), nil
+	case "badger":
+		return NewBadgerStore(dsn)
+	case "redis":
+		return NewRedisStore(dsn)
+	default:
+		return nil, fmt.Errorf("backend desconhecido: %q (use memory, badger ou redis)", backend)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGetFiresCallbackOnLazyExpiry cobre a regressão em que Get, ao notar
+// que um item expirou, disparava `go c.Delete(key)` -- que descarta
+// qualquer callback de SetWithCallback pendente sem chamá-lo, já que Delete
+// representa uma remoção explícita, não uma expiração. Como o timer de
+// limpeza (cleanup) e a checagem de relógio de Get disputam a mesma
+// ExpiresAt, se Get vencer a corrida o callback nunca dispararia.
+//
+// Reescreve ExpiresAt direto no store para o passado, sem tocar no heap de
+// expiração, simulando Get descobrindo a expiração por conta própria
+// enquanto o timer de limpeza ainda está armado para o instante real.
+func TestGetFiresCallbackOnLazyExpiry(t *testing.T) {
+	cache := NewNativeCache(time.Hour, time.Hour)
+	defer cache.Close()
+
+	fired := make(chan struct{}, 1)
+	cache.SetWithCallback("k", "v", time.Hour, func(key string, value interface{}) {
+		fired <- struct{}{}
+	})
+
+	cache.mutex.Lock()
+	item, _ := cache.store.Get("k")
+	item.ExpiresAt = time.Now().Add(-time.Second)
+	cache.store.Set("k", item)
+	cache.mutex.Unlock()
+
+	if _, found := cache.Get("k"); found {
+		t.Fatal("esperava miss para item expirado")
+	}
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("esperava que o callback de SetWithCallback disparasse quando Get descobre a expiração")
+	}
+}
+
+// TestDeleteDoesNotFireCallback garante que uma remoção explícita via
+// Delete continua sem disparar o callback de SetWithCallback -- só a
+// expiração dispara, seja pela limpeza, seja por expireKeyAsync (a
+// expiração descoberta por Get).
+func TestDeleteDoesNotFireCallback(t *testing.T) {
+	cache := NewNativeCache(time.Minute, time.Minute)
+	defer cache.Close()
+
+	fired := make(chan struct{}, 1)
+	cache.SetWithCallback("k", "v", time.Minute, func(key string, value interface{}) {
+		fired <- struct{}{}
+	})
+
+	cache.Delete("k")
+
+	select {
+	case <-fired:
+		t.Fatal("Delete explícito não deveria disparar o callback de expiração")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/gob"
+)
+
+// EvictionPolicy define como o cache escolhe o que remover quando atinge os
+// limites configurados por WithMaxItems/WithMaxBytes.
+type EvictionPolicy int
+
+const (
+	// LRU remove o item acessado há mais tempo.
+	LRU EvictionPolicy = iota
+	// LFU remove o item com a menor frequência de acesso.
+	LFU
+	// TinyLFU funciona como o LFU, mas só deixa um item novo expulsar a
+	// vítima quando ele já foi acessado com frequência igual ou maior; do
+	// contrário o próprio item recém-admitido é descartado. Isso evita que
+	// uma rajada de chaves usadas uma única vez (scan) expulse itens
+	// populares do cache.
+	TinyLFU
+)
+
+// Sizer estima, em bytes, o tamanho de um valor guardado no cache. O
+// resultado é calculado uma vez no Set e fica guardado em CacheItem.size,
+// usado para impor MaxBytes.
+type Sizer func(value interface{}) int64
+
+// defaultSizer estima o tamanho de value pelo comprimento da sua
+// codificação gob. Valores que não podem ser codificados (ex.: funções,
+// canais) contam como tamanho zero.
+func defaultSizer(value interface{}) int64 {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return 0
+	}
+	return int64(buf.Len())
+}
+
+// WithMaxItems limita o cache a n itens; ao ultrapassar o limite, itens são
+// removidos de acordo com a EvictionPolicy configurada (LRU por padrão).
+func WithMaxItems(n int) Option {
+	return func(c *NativeCache) {
+		c.maxItems = n
+	}
+}
+
+// WithMaxBytes limita o cache a n bytes, estimados pelo Sizer configurado
+// (ou pelo padrão, baseado em gob).
+func WithMaxBytes(n int64) Option {
+	return func(c *NativeCache) {
+		c.maxBytes = n
+	}
+}
+
+// WithEvictionPolicy escolhe a política usada quando MaxItems/MaxBytes é
+// excedido. Sem esta opção, o padrão é LRU.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(c *NativeCache) {
+		c.evictionPolicy = policy
+	}
+}
+
+// WithSizer troca a função usada para estimar o tamanho em bytes dos
+// valores guardados, usada para impor MaxBytes.
+func WithSizer(sizer Sizer) Option {
+	return func(c *NativeCache) {
+		c.sizer = sizer
+	}
+}
+
+// evictionEnabled reporta se o cache precisa rastrear LRU/frequência,
+// isto é, se algum limite de tamanho foi configurado.
+func (c *NativeCache) evictionEnabled() bool {
+	return c.maxItems > 0 || c.maxBytes > 0
+}
+
+// setMaxBytes atualiza o limite de MaxBytes em tempo de execução e evicta
+// imediatamente caso o novo limite já tenha sido ultrapassado. Usado por
+// MemoryBudget para rebalancear sub-caches sempre que uma nova é
+// registrada no mesmo orçamento.
+func (c *NativeCache) setMaxBytes(n int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	wasEnabled := c.evictionEnabled()
+	c.maxBytes = n
+	if c.evictionEnabled() && !wasEnabled {
+		// Primeira vez que algum limite é configurado neste cache: as
+		// estruturas de LRU/frequência só são alocadas em NewNativeCache
+		// quando evictionEnabled() já era true na criação.
+		c.lruList = list.New()
+		c.lruElems = make(map[string]*list.Element)
+		c.frequency = make(map[string]uint32)
+	}
+	c.evictionOn.Store(c.evictionEnabled())
+	c.evictIfNeededLocked("")
+}
+
+// touchLocked registra um acesso (Get ou Set) a key, atualizando a posição
+// na lista de LRU e a contagem de frequência. Deve ser chamado com
+// c.mutex travado.
+func (c *NativeCache) touchLocked(key string) {
+	if elem, ok := c.lruElems[key]; ok {
+		c.lruList.MoveToFront(elem)
+	} else {
+		c.lruElems[key] = c.lruList.PushFront(key)
+	}
+	c.frequency[key]++
+}
+
+// untrackLocked remove key da lista de LRU e da contagem de frequência.
+// Deve ser chamado com c.mutex travado.
+func (c *NativeCache) untrackLocked(key string) {
+	if elem, ok := c.lruElems[key]; ok {
+		c.lruList.Remove(elem)
+		delete(c.lruElems, key)
+	}
+	delete(c.frequency, key)
+}
+
+// evictIfNeededLocked aplica a política de evicção configurada até que o
+// cache volte a respeitar MaxItems/MaxBytes. newKey é a chave que acabou de
+// ser gravada, usada pelo filtro de admissão do TinyLFU. Deve ser chamado
+// com c.mutex travado.
+func (c *NativeCache) evictIfNeededLocked(newKey string) {
+	for c.overLimitLocked() {
+		victim, ok := c.pickVictimLocked(newKey)
+		if !ok {
+			return
+		}
+
+		item, _ := c.store.Get(victim)
+		c.store.Delete(victim)
+		c.unscheduleExpirationLocked(victim)
+		c.untrackLocked(victim)
+		c.appendWALLocked(walRecord{Op: walOpDelete, Key: victim})
+		c.stats.BytesInUse -= item.size
+		c.stats.Evictions++
+		if c.metrics != nil {
+			c.metrics.ObserveEviction(c.metricsName())
+		}
+
+		if victim == newKey {
+			// O filtro de admissão do TinyLFU rejeitou o próprio item
+			// recém-inserido; não há mais nada a fazer.
+			return
+		}
+	}
+}
+
+// overLimitLocked reporta se o cache ultrapassa MaxItems ou MaxBytes.
+func (c *NativeCache) overLimitLocked() bool {
+	if c.maxItems > 0 && c.store.Len() > c.maxItems {
+		return true
+	}
+	if c.maxBytes > 0 && c.stats.BytesInUse > c.maxBytes {
+		return true
+	}
+	return false
+}
+
+// pickVictimLocked escolhe a próxima chave a ser removida de acordo com
+// c.evictionPolicy. Retorna ok=false se não houver nada para remover.
+func (c *NativeCache) pickVictimLocked(newKey string) (string, bool) {
+	switch c.evictionPolicy {
+	case LFU, TinyLFU:
+		victim, minFreq, found := "", uint32(0), false
+		c.store.Iterate(func(key string, _ CacheItem) bool {
+			freq := c.frequency[key]
+			if !found || freq < minFreq {
+				victim, minFreq, found = key, freq, true
+			}
+			return true
+		})
+		if !found {
+			return "", false
+		}
+		if c.evictionPolicy == TinyLFU && victim != newKey && c.frequency[newKey] < minFreq {
+			return newKey, true
+		}
+		return victim, true
+
+	default: // LRU
+		elem := c.lruList.Back()
+		if elem == nil {
+			return "", false
+		}
+		return elem.Value.(string), true
+	}
+}
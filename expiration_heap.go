@@ -0,0 +1,44 @@
+package main
+
+import "time"
+
+// expirationEntry é um nó da fila de prioridade de expiração, mapeando uma
+// chave do cache ao instante em que ela deve ser removida.
+type expirationEntry struct {
+	key       string
+	expiresAt time.Time
+	index     int
+}
+
+// expirationHeap é um min-heap ordenado por expiresAt. Ele permite que a
+// goroutine de limpeza descubra em O(1) qual item expira primeiro e durma
+// até esse instante, em vez de varrer o mapa inteiro a cada tick.
+type expirationHeap []*expirationEntry
+
+func (h expirationHeap) Len() int { return len(h) }
+
+func (h expirationHeap) Less(i, j int) bool {
+	return h[i].expiresAt.Before(h[j].expiresAt)
+}
+
+func (h expirationHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expirationHeap) Push(x interface{}) {
+	entry := x.(*expirationEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expirationHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*h = old[:n-1]
+	return entry
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// WithEarlyRefreshWindow habilita o recomputo probabilístico do Refresh:
+// itens a menos de window da expiração passam a ter chance crescente de
+// disparar o loader em background. window <= 0 (o padrão) desativa o
+// recomputo antecipado.
+func WithEarlyRefreshWindow(window time.Duration) Option {
+	return func(c *NativeCache) {
+		c.earlyRefreshWindow = window
+	}
+}
+
+// GetOrLoad recupera key do cache; em caso de miss, calcula o valor com
+// loader e grava o resultado com ttl. Chamadas concorrentes para a mesma
+// key colapsam em uma única execução de loader via singleflight, evitando
+// que uma rajada de misses simultâneos recalcule o mesmo valor N vezes
+// (cache stampede). O bool retornado indica se o valor já estava em cache
+// (hit) ou veio de loader (miss); Get é chamado exatamente uma vez, para
+// que esse resultado reflita de fato o que foi servido.
+func (c *NativeCache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) (interface{}, bool, error) {
+	if value, exists := c.Get(key); exists {
+		return value, true, nil
+	}
+
+	value, err, _ := c.sfGroup.Do(key, func() (interface{}, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Set(key, value, ttl)
+		return value, nil
+	})
+
+	return value, false, err
+}
+
+// Refresh recomputa key em background, antes que ela expire, quando ela
+// está a menos de WithEarlyRefreshWindow da expiração. A decisão de
+// disparar é probabilística (inspirada no XFetch): quanto mais perto do
+// vencimento, maior a chance de recomputar, o que espalha a carga de
+// recálculo no tempo em vez de deixar tudo acontecer exatamente no
+// instante da expiração. Um Refresh chamado fora da janela, ou sem
+// WithEarlyRefreshWindow configurado, é um no-op.
+func (c *NativeCache) Refresh(key string, loader func(ctx context.Context) (interface{}, error), ttl time.Duration) {
+	c.mutex.RLock()
+	item, exists := c.store.Get(key)
+	window := c.earlyRefreshWindow
+	c.mutex.RUnlock()
+
+	if !exists || window <= 0 {
+		return
+	}
+
+	remaining := time.Until(item.ExpiresAt)
+	if remaining <= 0 || remaining > window {
+		return
+	}
+
+	probability := 1 - float64(remaining)/float64(window)
+	if rand.Float64() > probability {
+		return
+	}
+
+	go func() {
+		c.sfGroup.Do(key, func() (interface{}, error) {
+			value, err := loader(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			c.Set(key, value, ttl)
+			return value, nil
+		})
+	}()
+}
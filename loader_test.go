@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestGetOrLoadReportsHitAndMiss cobre a regressão em que GetOrLoad chamava
+// Get duas vezes (uma no handler, outra internamente), inflando Hits/Misses
+// e deixando o hit/miss reportado fora de sincronia com o valor realmente
+// servido. Com uma única chamada a Get, o hit reportado aqui deve bater com
+// as estatísticas do cache.
+func TestGetOrLoadReportsHitAndMiss(t *testing.T) {
+	cache := NewNativeCache(time.Minute, time.Minute)
+	defer cache.Close()
+
+	loader := func(ctx context.Context) (interface{}, error) { return "carregado", nil }
+
+	value, hit, err := cache.GetOrLoad(context.Background(), "k", time.Minute, loader)
+	if err != nil || hit || value != "carregado" {
+		t.Fatalf("primeira chamada deveria ser miss com \"carregado\", got value=%v hit=%v err=%v", value, hit, err)
+	}
+
+	value, hit, err = cache.GetOrLoad(context.Background(), "k", time.Minute, loader)
+	if err != nil || !hit || value != "carregado" {
+		t.Fatalf("segunda chamada deveria ser hit com \"carregado\", got value=%v hit=%v err=%v", value, hit, err)
+	}
+
+	stats := cache.GetStats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("esperava exatamente 1 hit e 1 miss, got Hits=%d Misses=%d", stats.Hits, stats.Misses)
+	}
+}
+
+// TestGetOrLoadCollapsesConcurrentMisses garante que uma rajada de misses
+// simultâneos para a mesma key dispara loader uma única vez.
+func TestGetOrLoadCollapsesConcurrentMisses(t *testing.T) {
+	cache := NewNativeCache(time.Minute, time.Minute)
+	defer cache.Close()
+
+	var calls int64
+	loader := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "valor", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetOrLoad(context.Background(), "stampede", time.Minute, loader)
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Fatalf("esperava loader chamado 1 vez, got %d", got)
+	}
+}
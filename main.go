@@ -1,11 +1,19 @@
 package main
 
 import (
+	"container/heap"
+	"container/list"
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/williamkoller/go-native-cache/metrics"
 )
 
 // CacheItem representa um item no cache com TTL
@@ -13,6 +21,10 @@ type CacheItem struct {
 	Value     interface{} `json:"value"`
 	ExpiresAt time.Time   `json:"expires_at"`
 	CreatedAt time.Time   `json:"created_at"`
+
+	// size é o tamanho estimado de Value em bytes, calculado uma vez por
+	// Sizer no momento do Set e usado para impor MaxBytes.
+	size int64
 }
 
 // IsExpired verifica se o item expirou
@@ -22,13 +34,59 @@ func (item *CacheItem) IsExpired() bool {
 
 // NativeCache estrutura principal do cache
 type NativeCache struct {
-	items       map[string]*CacheItem
+	store       Store
 	mutex       sync.RWMutex
 	defaultTTL  time.Duration
 	cleanupTick time.Duration
 	ctx         context.Context
 	cancel      context.CancelFunc
 	stats       CacheStats
+
+	// expHeap/expIndex mantêm uma fila de prioridade das expirações, para
+	// que a limpeza não precise varrer todo o mapa a cada execução.
+	expHeap  expirationHeap
+	expIndex map[string]*expirationEntry
+	// wake acorda a goroutine de limpeza assim que uma expiração mais
+	// próxima que a atualmente agendada é inserida.
+	wake chan struct{}
+
+	// callbacks guarda os callbacks pendentes de SetWithCallback, disparados
+	// pela própria goroutine de limpeza quando o item expira.
+	callbacks map[string]func(key string, value interface{})
+
+	// Campos usados quando há um limite de tamanho (MaxItems/MaxBytes).
+	// Deixados zerados, o cache cresce livremente como antes.
+	maxItems       int
+	maxBytes       int64
+	evictionPolicy EvictionPolicy
+	sizer          Sizer
+	lruList        *list.List
+	lruElems       map[string]*list.Element
+	frequency      map[string]uint32
+	// evictionOn espelha evictionEnabled() de forma atômica, para que Get
+	// possa decidir entre RLock e Lock sem precisar travar o mutex antes.
+	// setMaxBytes é o único jeito de mudar maxBytes depois da criação do
+	// cache, e mantém este campo atualizado.
+	evictionOn atomic.Bool
+
+	// name identifica o cache nas métricas Prometheus; metrics é nil
+	// enquanto WithPrometheusRegistry não for usado.
+	name    string
+	metrics *metrics.Collector
+
+	// sfGroup colapsa chamadas concorrentes de loader para a mesma chave
+	// em GetOrLoad/Refresh, evitando cache stampede.
+	sfGroup singleflight.Group
+	// earlyRefreshWindow é a janela antes da expiração em que Refresh pode
+	// disparar um recomputo probabilístico (0 desativa).
+	earlyRefreshWindow time.Duration
+
+	// walPath/walSync/walFile/walSize só são usados quando WithWAL é
+	// passado a NewNativeCache; walFile nil significa WAL desativado.
+	walPath string
+	walSync WALSync
+	walFile *os.File
+	walSize int64
 }
 
 // CacheStats estatísticas do cache
@@ -39,19 +97,50 @@ type CacheStats struct {
 	Deletes      int64 `json:"deletes"`
 	Cleanups     int64 `json:"cleanups"`
 	ItemsCleaned int64 `json:"items_cleaned"`
+	Evictions    int64 `json:"evictions"`
+	BytesInUse   int64 `json:"bytes_in_use"`
 }
 
-// NewNativeCache cria uma nova instância do cache
-func NewNativeCache(defaultTTL, cleanupInterval time.Duration) *NativeCache {
+// NewNativeCache cria uma nova instância do cache. Por padrão os itens
+// ficam em memória; passe WithStore para usar outro backend (disco, Redis,
+// etc.) mantendo a mesma API.
+func NewNativeCache(defaultTTL, cleanupInterval time.Duration, opts ...Option) *NativeCache {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	cache := &NativeCache{
-		items:       make(map[string]*CacheItem),
 		defaultTTL:  defaultTTL,
 		cleanupTick: cleanupInterval,
 		ctx:         ctx,
 		cancel:      cancel,
 		stats:       CacheStats{},
+		expIndex:    make(map[string]*expirationEntry),
+		wake:        make(chan struct{}, 1),
+		callbacks:   make(map[string]func(key string, value interface{})),
+	}
+
+	for _, opt := range opts {
+		opt(cache)
+	}
+
+	if cache.store == nil {
+		cache.store = newMemoryStore()
+	}
+	if cache.sizer == nil {
+		cache.sizer = defaultSizer
+	}
+	if cache.evictionEnabled() {
+		cache.lruList = list.New()
+		cache.lruElems = make(map[string]*list.Element)
+		cache.frequency = make(map[string]uint32)
+		cache.evictionOn.Store(true)
+	}
+
+	if cache.walPath != "" {
+		// Restaurar o estado anterior é o propósito de WithWAL; um erro
+		// aqui normalmente significa arquivos corrompidos ou ausentes, e
+		// não deve impedir o cache de subir (vazio) para o processo atual.
+		cache.restorePersisted()
+		cache.openWAL()
 	}
 
 	// Inicia o processo de limpeza automática
@@ -63,52 +152,185 @@ func NewNativeCache(defaultTTL, cleanupInterval time.Duration) *NativeCache {
 // Set adiciona ou atualiza um item no cache
 func (c *NativeCache) Set(key string, value interface{}, ttl ...time.Duration) {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
 
 	expiration := c.defaultTTL
 	if len(ttl) > 0 {
 		expiration = ttl[0]
 	}
 
-	c.items[key] = &CacheItem{
+	expiresAt := time.Now().Add(expiration)
+	item := CacheItem{
 		Value:     value,
-		ExpiresAt: time.Now().Add(expiration),
+		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
 	}
+	if c.evictionEnabled() {
+		item.size = c.sizer(value)
+	}
+
+	old, hadOld := c.store.Get(key)
+	c.store.Set(key, item)
 
 	c.stats.Sets++
+	isEarliest := c.scheduleExpirationLocked(key, expiresAt)
+	if c.evictionEnabled() {
+		if hadOld {
+			c.stats.BytesInUse -= old.size
+		}
+		c.stats.BytesInUse += item.size
+		c.touchLocked(key)
+		c.evictIfNeededLocked(key)
+	}
+	c.appendWALLocked(walRecord{Op: walOpSet, Key: key, Value: value, ExpiresAt: expiresAt})
+	c.reportGaugesLocked()
+	c.mutex.Unlock()
+
+	if c.metrics != nil {
+		c.metrics.ObserveSet(c.metricsName())
+	}
+	if isEarliest {
+		c.wakeCleanup()
+	}
+}
+
+// reportGaugesLocked atualiza as métricas Prometheus cache_items/
+// cache_bytes com o estado atual do cache. Deve ser chamado com c.mutex
+// travado; é um no-op quando nenhum Registry foi configurado.
+func (c *NativeCache) reportGaugesLocked() {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.SetItems(c.metricsName(), float64(c.store.Len()))
+	c.metrics.SetBytes(c.metricsName(), float64(c.stats.BytesInUse))
+}
+
+// scheduleExpirationLocked insere ou atualiza a entrada de key no heap de
+// expiração. Deve ser chamado com c.mutex já travado. Retorna true quando a
+// expiração passou a ser a mais próxima do heap, indicando que a goroutine
+// de limpeza precisa ser acordada para reagendar seu timer.
+func (c *NativeCache) scheduleExpirationLocked(key string, expiresAt time.Time) bool {
+	if entry, ok := c.expIndex[key]; ok {
+		entry.expiresAt = expiresAt
+		heap.Fix(&c.expHeap, entry.index)
+	} else {
+		entry = &expirationEntry{key: key, expiresAt: expiresAt}
+		heap.Push(&c.expHeap, entry)
+		c.expIndex[key] = entry
+	}
+
+	return c.expHeap[0].key == key
+}
+
+// unscheduleExpirationLocked remove a entrada de key do heap de expiração,
+// se houver uma. Deve ser chamado com c.mutex já travado.
+func (c *NativeCache) unscheduleExpirationLocked(key string) {
+	if entry, ok := c.expIndex[key]; ok {
+		heap.Remove(&c.expHeap, entry.index)
+		delete(c.expIndex, key)
+	}
+	delete(c.callbacks, key)
+}
+
+// wakeCleanup notifica a goroutine de limpeza de que uma expiração mais
+// próxima foi agendada, sem bloquear caso ela já esteja acordada.
+func (c *NativeCache) wakeCleanup() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
 }
 
-// Get recupera um item do cache
+// Get recupera um item do cache. Quando uma política de evicção está
+// configurada, o acesso também atualiza a posição do item na LRU/contagem
+// de frequência, por isso usa o lock de escrita em vez do de leitura; sem
+// evicção configurada (o caso comum), usa o lock de leitura e permite
+// leituras concorrentes.
 func (c *NativeCache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	if c.evictionOn.Load() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+	} else {
+		c.mutex.RLock()
+		defer c.mutex.RUnlock()
+	}
 
-	item, exists := c.items[key]
+	item, exists := c.store.Get(key)
 	if !exists {
 		c.stats.Misses++
+		if c.metrics != nil {
+			c.metrics.ObserveMiss(c.metricsName())
+		}
 		return nil, false
 	}
 
 	if item.IsExpired() {
 		c.stats.Misses++
-		// Remove item expirado durante o get
-		go c.Delete(key)
+		if c.metrics != nil {
+			c.metrics.ObserveMiss(c.metricsName())
+		}
+		// Remove item expirado durante o get, disparando qualquer
+		// callback de SetWithCallback pendente (ver expireKeyAsync).
+		go c.expireKeyAsync(key)
 		return nil, false
 	}
 
+	if c.evictionEnabled() {
+		c.touchLocked(key)
+	}
+
 	c.stats.Hits++
+	if c.metrics != nil {
+		c.metrics.ObserveHit(c.metricsName())
+	}
 	return item.Value, true
 }
 
+// deleteLocked remove key do store e das estruturas auxiliares (heap de
+// expiração, callback pendente, LRU/frequência), grava o delete no WAL e
+// atualiza estatísticas/métricas. Deve ser chamado com c.mutex já travado.
+// Retorna o item removido e se ele existia.
+func (c *NativeCache) deleteLocked(key string) (CacheItem, bool) {
+	item, exists := c.store.Get(key)
+	if !exists {
+		return CacheItem{}, false
+	}
+
+	c.store.Delete(key)
+	c.unscheduleExpirationLocked(key)
+	if c.evictionEnabled() {
+		c.untrackLocked(key)
+		c.stats.BytesInUse -= item.size
+	}
+	c.stats.Deletes++
+	c.appendWALLocked(walRecord{Op: walOpDelete, Key: key})
+	c.reportGaugesLocked()
+
+	if c.metrics != nil {
+		c.metrics.ObserveDelete(c.metricsName())
+	}
+	return item, true
+}
+
 // Delete remove um item do cache
 func (c *NativeCache) Delete(key string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.deleteLocked(key)
+}
 
-	if _, exists := c.items[key]; exists {
-		delete(c.items, key)
-		c.stats.Deletes++
+// expireKeyAsync remove key porque Get constatou que ela expirou na
+// leitura, disparando o callback pendente de SetWithCallback (se houver)
+// antes de descartá-lo -- ao contrário de Delete, que é uma remoção
+// explícita do chamador e não representa uma expiração. Roda em goroutine
+// própria porque Get pode ter retornado segurando apenas o RLock.
+func (c *NativeCache) expireKeyAsync(key string) {
+	c.mutex.Lock()
+	callback, hasCallback := c.callbacks[key]
+	item, existed := c.deleteLocked(key)
+	c.mutex.Unlock()
+
+	if existed && hasCallback {
+		callback(key, item.Value)
 	}
 }
 
@@ -117,15 +339,32 @@ func (c *NativeCache) Clear() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.items = make(map[string]*CacheItem)
+	keys := make([]string, 0, c.store.Len())
+	c.store.Iterate(func(key string, _ CacheItem) bool {
+		keys = append(keys, key)
+		return true
+	})
+	for _, key := range keys {
+		c.store.Delete(key)
+	}
+
+	c.expHeap = nil
+	c.expIndex = make(map[string]*expirationEntry)
+	c.callbacks = make(map[string]func(key string, value interface{}))
 	c.stats = CacheStats{}
+
+	if c.evictionEnabled() {
+		c.lruList = list.New()
+		c.lruElems = make(map[string]*list.Element)
+		c.frequency = make(map[string]uint32)
+	}
 }
 
 // Size retorna o número de itens no cache
 func (c *NativeCache) Size() int {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
-	return len(c.items)
+	return c.store.Len()
 }
 
 // Keys retorna todas as chaves do cache
@@ -133,10 +372,11 @@ func (c *NativeCache) Keys() []string {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	keys := make([]string, 0, len(c.items))
-	for key := range c.items {
+	keys := make([]string, 0, c.store.Len())
+	c.store.Iterate(func(key string, _ CacheItem) bool {
 		keys = append(keys, key)
-	}
+		return true
+	})
 	return keys
 }
 
@@ -145,7 +385,7 @@ func (c *NativeCache) Has(key string) bool {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	item, exists := c.items[key]
+	item, exists := c.store.Get(key)
 	if !exists {
 		return false
 	}
@@ -160,75 +400,155 @@ func (c *NativeCache) GetStats() CacheStats {
 	return c.stats
 }
 
-// startCleanup inicia o processo de limpeza automática
+// startCleanup inicia o processo de limpeza automática. Em vez de acordar em
+// um intervalo fixo, ele dorme até a expiração mais próxima conhecida pelo
+// heap, disparando a limpeza o quanto antes. Um Set com uma expiração mais
+// próxima reinicia a espera através do canal wake.
 func (c *NativeCache) startCleanup() {
-	ticker := time.NewTicker(c.cleanupTick)
-	defer ticker.Stop()
-
 	for {
+		c.mutex.RLock()
+		delay := c.nextCleanupDelayLocked()
+		c.mutex.RUnlock()
+
+		timer := time.NewTimer(delay)
 		select {
 		case <-c.ctx.Done():
+			timer.Stop()
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			c.cleanup()
+		case <-c.wake:
+			timer.Stop()
 		}
 	}
 }
 
-// cleanup remove itens expirados
+// nextCleanupDelayLocked calcula quanto tempo falta até a próxima expiração
+// conhecida. Deve ser chamado com c.mutex travado (leitura ou escrita). Na
+// ausência de itens, cai de volta para o intervalo padrão cleanupTick, só
+// para manter a goroutine viva e reagir a um Set futuro.
+func (c *NativeCache) nextCleanupDelayLocked() time.Duration {
+	if len(c.expHeap) == 0 {
+		return c.cleanupTick
+	}
+	if delay := time.Until(c.expHeap[0].expiresAt); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// cleanup remove apenas os itens cujo prazo já venceu, retirando-os do topo
+// do heap de expiração em vez de varrer o mapa inteiro.
 func (c *NativeCache) cleanup() {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
 
 	now := time.Now()
 	itemsCleaned := int64(0)
+	var fired []func()
+
+	for len(c.expHeap) > 0 && !c.expHeap[0].expiresAt.After(now) {
+		entry := heap.Pop(&c.expHeap).(*expirationEntry)
+		delete(c.expIndex, entry.key)
+
+		item, _ := c.store.Get(entry.key)
+		c.store.Delete(entry.key)
+		itemsCleaned++
 
-	for key, item := range c.items {
-		if now.After(item.ExpiresAt) {
-			delete(c.items, key)
-			itemsCleaned++
+		if c.evictionEnabled() {
+			c.untrackLocked(entry.key)
+			c.stats.BytesInUse -= item.size
+		}
+
+		if callback, ok := c.callbacks[entry.key]; ok {
+			delete(c.callbacks, entry.key)
+			key, value := entry.key, item.Value
+			fired = append(fired, func() { callback(key, value) })
 		}
 	}
 
 	c.stats.Cleanups++
 	c.stats.ItemsCleaned += itemsCleaned
+	c.mutex.Unlock()
+
+	for _, callback := range fired {
+		go callback()
+	}
 }
 
-// Close para o cache e limpa recursos
+// Close para o cache, libera o backend de armazenamento e limpa recursos.
+// Quando WithWAL foi usado, um último snapshot é gravado antes do WAL ser
+// fechado, para que o próximo restart reaplique o mínimo possível dele.
 func (c *NativeCache) Close() {
 	c.cancel()
+	c.closeWAL()
 	c.Clear()
+	c.store.Close()
 }
 
-// ToJSON exporta o cache para JSON (para debug/backup)
+// ToJSON exporta o cache para JSON (para debug/inspeção). Para persistir o
+// cache entre restarts, use SaveSnapshot/LoadSnapshot ou WithWAL.
 func (c *NativeCache) ToJSON() ([]byte, error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
+	items := make(map[string]CacheItem, c.store.Len())
+	c.store.Iterate(func(key string, item CacheItem) bool {
+		items[key] = item
+		return true
+	})
+
 	data := struct {
-		Items map[string]*CacheItem `json:"items"`
-		Stats CacheStats            `json:"stats"`
-		Size  int                   `json:"size"`
+		Items map[string]CacheItem `json:"items"`
+		Stats CacheStats           `json:"stats"`
+		Size  int                  `json:"size"`
 	}{
-		Items: c.items,
+		Items: items,
 		Stats: c.stats,
-		Size:  len(c.items),
+		Size:  c.store.Len(),
 	}
 
 	return json.MarshalIndent(data, "", "  ")
 }
 
-// SetWithCallback define um item com callback quando expirar
+// SetWithCallback define um item com callback quando expirar. O callback é
+// disparado pela própria goroutine de limpeza assim que o item vence, sem
+// precisar de uma goroutine dedicada por item.
 func (c *NativeCache) SetWithCallback(key string, value interface{}, ttl time.Duration, callback func(key string, value interface{})) {
-	c.Set(key, value, ttl)
+	c.mutex.Lock()
+
+	expiresAt := time.Now().Add(ttl)
+	item := CacheItem{
+		Value:     value,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if c.evictionEnabled() {
+		item.size = c.sizer(value)
+	}
+
+	old, hadOld := c.store.Get(key)
+	c.store.Set(key, item)
+	c.stats.Sets++
 
-	// Goroutine para executar callback quando expirar
-	go func() {
-		time.Sleep(ttl)
-		if _, exists := c.Get(key); !exists && callback != nil {
-			callback(key, value)
+	if callback != nil {
+		c.callbacks[key] = callback
+	}
+
+	isEarliest := c.scheduleExpirationLocked(key, expiresAt)
+	if c.evictionEnabled() {
+		if hadOld {
+			c.stats.BytesInUse -= old.size
 		}
-	}()
+		c.stats.BytesInUse += item.size
+		c.touchLocked(key)
+		c.evictIfNeededLocked(key)
+	}
+	c.appendWALLocked(walRecord{Op: walOpSet, Key: key, Value: value, ExpiresAt: expiresAt})
+	c.mutex.Unlock()
+
+	if isEarliest {
+		c.wakeCleanup()
+	}
 }
 
 // GetOrSet recupera um item ou define um novo se não existir
@@ -243,8 +563,16 @@ func (c *NativeCache) GetOrSet(key string, defaultValue interface{}, ttl ...time
 
 // Função principal - exemplo de uso
 func main() {
-	// Cria cache com TTL padrão de 5 minutos e limpeza a cada 1 minuto
-	cache := NewNativeCache(5*time.Minute, 1*time.Minute)
+	// Tipos guardados como interface{} em CacheItem.Value precisam estar
+	// registrados para sobreviver a um snapshot/WAL.
+	RegisterType(map[string]string{})
+	RegisterType("")
+
+	// Cria cache com TTL padrão de 5 minutos; a limpeza acorda a cada
+	// expiração, e 1 minuto é só a espera enquanto não há itens. O
+	// WAL em cache.wal permite que o conteúdo sobreviva a um restart do
+	// processo, tornando o "rodando 24/7" real de fato.
+	cache := NewNativeCache(5*time.Minute, 1*time.Minute, WithWAL("cache.wal", WALSyncAlways))
 	defer cache.Close()
 
 	fmt.Println("🚀 Cache nativo Go iniciado - rodando 24/7!")
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryBudget distribui um orçamento total de memória entre várias
+// sub-caches nomeadas, de acordo com pesos relativos, para que o usuário
+// configure um único número (o orçamento total) em vez de um MaxBytes por
+// cache. O orçamento é dono das sub-caches que cria: cada SubCache
+// rebalanceia o MaxBytes de todas as outras, para que a soma continue
+// respeitando totalBytes mesmo depois de novas entrantes.
+type MemoryBudget struct {
+	mutex      sync.Mutex
+	totalBytes int64
+	weights    map[string]float64
+	caches     map[string]*NativeCache
+}
+
+// NewMemoryBudget cria um orçamento de memória de totalBytes bytes, a ser
+// repartido entre as sub-caches criadas por SubCache.
+func NewMemoryBudget(totalBytes int64) *MemoryBudget {
+	return &MemoryBudget{
+		totalBytes: totalBytes,
+		weights:    make(map[string]float64),
+		caches:     make(map[string]*NativeCache),
+	}
+}
+
+// SubCache cria um NativeCache chamado name com MaxBytes proporcional a
+// weight em relação à soma dos pesos de todas as sub-caches registradas
+// neste orçamento, e rebalanceia o MaxBytes de todas as sub-caches já
+// criadas para que a soma continue respeitando totalBytes. Chamar SubCache
+// novamente com o mesmo name substitui o peso anterior.
+func (b *MemoryBudget) SubCache(name string, weight float64, defaultTTL, cleanupInterval time.Duration, opts ...Option) *NativeCache {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.weights[name] = weight
+	opts = append(opts, WithMaxBytes(b.maxBytesLocked(name)))
+	cache := NewNativeCache(defaultTTL, cleanupInterval, opts...)
+	b.caches[name] = cache
+
+	b.rebalanceLocked()
+	return cache
+}
+
+// maxBytesLocked calcula o MaxBytes de name de acordo com os pesos atuais.
+// Deve ser chamado com b.mutex travado.
+func (b *MemoryBudget) maxBytesLocked(name string) int64 {
+	totalWeight := 0.0
+	for _, w := range b.weights {
+		totalWeight += w
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return int64(float64(b.totalBytes) * b.weights[name] / totalWeight)
+}
+
+// rebalanceLocked reaplica o MaxBytes de cada sub-cache já criada, de
+// acordo com os pesos atuais. Deve ser chamado com b.mutex travado.
+func (b *MemoryBudget) rebalanceLocked() {
+	for name, cache := range b.caches {
+		cache.setMaxBytes(b.maxBytesLocked(name))
+	}
+}
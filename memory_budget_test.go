@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemoryBudgetRebalancesOnEachSubCache cobre a regressão em que cada
+// SubCache só calculava seu próprio MaxBytes, ignorando as sub-caches já
+// criadas: registrar três pesos iguais fazia a soma dos limites passar bem
+// de totalBytes, em vez de respeitá-lo.
+func TestMemoryBudgetRebalancesOnEachSubCache(t *testing.T) {
+	budget := NewMemoryBudget(900)
+
+	a := budget.SubCache("a", 1, time.Minute, time.Minute)
+	defer a.Close()
+	b := budget.SubCache("b", 1, time.Minute, time.Minute)
+	defer b.Close()
+	c := budget.SubCache("c", 1, time.Minute, time.Minute)
+	defer c.Close()
+
+	if a.maxBytes != 300 || b.maxBytes != 300 || c.maxBytes != 300 {
+		t.Fatalf("esperava 300 bytes por sub-cache, obteve a=%d b=%d c=%d", a.maxBytes, b.maxBytes, c.maxBytes)
+	}
+
+	if total := a.maxBytes + b.maxBytes + c.maxBytes; total != budget.totalBytes {
+		t.Fatalf("soma dos MaxBytes (%d) deveria ser igual ao orçamento total (%d)", total, budget.totalBytes)
+	}
+}
+
+// TestGetWithoutEvictionUsesReadLock garante que, sem MaxItems/MaxBytes
+// configurado, Get não serializa leituras concorrentes atrás de um lock de
+// escrita.
+func TestGetWithoutEvictionUsesReadLock(t *testing.T) {
+	cache := NewNativeCache(time.Minute, time.Minute)
+	defer cache.Close()
+	cache.Set("k", "v")
+
+	done := make(chan struct{})
+	cache.mutex.RLock()
+	go func() {
+		cache.Get("k")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Get bloqueou atrás de um RLock já adquirido por outro leitor")
+	}
+	cache.mutex.RUnlock()
+}
@@ -0,0 +1,125 @@
+// Package metrics expõe as métricas Prometheus de um NativeCache. Ele não
+// depende do pacote principal para evitar um import cycle; o NativeCache é
+// quem chama os métodos de Collector a cada operação.
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector agrupa as métricas Prometheus de um ou mais caches. Todas as
+// séries são rotuladas por cache, para que várias instâncias compartilhem
+// o mesmo Collector/registry sem colidir.
+type Collector struct {
+	hits      *prometheus.CounterVec
+	misses    *prometheus.CounterVec
+	sets      *prometheus.CounterVec
+	deletes   *prometheus.CounterVec
+	evictions *prometheus.CounterVec
+
+	items *prometheus.GaugeVec
+	bytes *prometheus.GaugeVec
+
+	getDuration *prometheus.HistogramVec
+}
+
+// collectors memoriza o Collector já registrado em cada *prometheus.Registry
+// (a chave nil representa o DefaultRegisterer), para que New seja seguro de
+// chamar mais de uma vez com o mesmo reg.
+var (
+	collectorsMu sync.Mutex
+	collectors   = make(map[*prometheus.Registry]*Collector)
+)
+
+// New retorna o Collector registrado em reg (ou no DefaultRegisterer, se reg
+// for nil), criando-o e registrando suas métricas na primeira chamada.
+// Chamadas seguintes com o mesmo reg reusam esse Collector em vez de
+// registrar as mesmas métricas de novo -- o registerer.MustRegister de uma
+// segunda criação entraria em pânico com "duplicate metrics collector
+// registration attempted", justamente no cenário de várias instâncias de
+// cache compartilhando um registry que este tipo existe para suportar.
+func New(reg *prometheus.Registry) *Collector {
+	collectorsMu.Lock()
+	defer collectorsMu.Unlock()
+
+	if c, ok := collectors[reg]; ok {
+		return c
+	}
+
+	c := &Collector{
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Número total de acertos de cache (Get encontrou um item válido).",
+		}, []string{"cache"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Número total de erros de cache (Get não encontrou, ou o item expirou).",
+		}, []string{"cache"}),
+		sets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_sets_total",
+			Help: "Número total de itens gravados no cache.",
+		}, []string{"cache"}),
+		deletes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_deletes_total",
+			Help: "Número total de itens removidos explicitamente do cache.",
+		}, []string{"cache"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_evictions_total",
+			Help: "Número total de itens removidos por exceder MaxItems/MaxBytes.",
+		}, []string{"cache"}),
+		items: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cache_items",
+			Help: "Número atual de itens no cache.",
+		}, []string{"cache"}),
+		bytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cache_bytes",
+			Help: "Tamanho estimado, em bytes, dos itens atualmente no cache.",
+		}, []string{"cache"}),
+		getDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "cache_get_duration_seconds",
+			Help: "Duração das chamadas a Get.",
+		}, []string{"cache"}),
+	}
+
+	registerer := prometheus.Registerer(prometheus.DefaultRegisterer)
+	if reg != nil {
+		registerer = reg
+	}
+	registerer.MustRegister(
+		c.hits, c.misses, c.sets, c.deletes, c.evictions,
+		c.items, c.bytes, c.getDuration,
+	)
+
+	collectors[reg] = c
+	return c
+}
+
+// ObserveHit registra um acerto de cache para o cache chamado name.
+func (c *Collector) ObserveHit(name string) { c.hits.WithLabelValues(name).Inc() }
+
+// ObserveMiss registra um erro de cache para o cache chamado name.
+func (c *Collector) ObserveMiss(name string) { c.misses.WithLabelValues(name).Inc() }
+
+// ObserveSet registra uma gravação no cache chamado name.
+func (c *Collector) ObserveSet(name string) { c.sets.WithLabelValues(name).Inc() }
+
+// ObserveDelete registra uma remoção explícita no cache chamado name.
+func (c *Collector) ObserveDelete(name string) { c.deletes.WithLabelValues(name).Inc() }
+
+// ObserveEviction registra uma remoção por limite de tamanho no cache
+// chamado name.
+func (c *Collector) ObserveEviction(name string) { c.evictions.WithLabelValues(name).Inc() }
+
+// SetItems atualiza o número atual de itens do cache chamado name.
+func (c *Collector) SetItems(name string, n float64) { c.items.WithLabelValues(name).Set(n) }
+
+// SetBytes atualiza o tamanho estimado em bytes do cache chamado name.
+func (c *Collector) SetBytes(name string, n float64) { c.bytes.WithLabelValues(name).Set(n) }
+
+// ObserveGetDuration registra a duração de uma chamada a Get no cache
+// chamado name.
+func (c *Collector) ObserveGetDuration(name string, seconds float64) {
+	c.getDuration.WithLabelValues(name).Observe(seconds)
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestNewReusesCollectorPerRegistry cobre a regressão em que duas instâncias
+// de cache compartilhando o mesmo *prometheus.Registry entravam em pânico
+// com "duplicate metrics collector registration attempted" -- exatamente o
+// cenário que o doc comment de Collector promete suportar.
+func TestNewReusesCollectorPerRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	first := New(reg)
+	second := New(reg)
+
+	if first != second {
+		t.Fatal("esperava que New reusasse o mesmo Collector para o mesmo Registry")
+	}
+}
+
+// TestNewReusesDefaultRegisterer cobre o mesmo cenário para reg == nil, que
+// cai no DefaultRegisterer.
+func TestNewReusesDefaultRegisterer(t *testing.T) {
+	first := New(nil)
+	second := New(nil)
+
+	if first != second {
+		t.Fatal("esperava que New reusasse o mesmo Collector para o DefaultRegisterer")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// defaultShardCount é usado quando NewShardedCache recebe uma contagem de
+// shards inválida (zero, negativa ou não potência de dois).
+const defaultShardCount = 256
+
+// ShardedCache distribui as chaves entre várias instâncias independentes de
+// NativeCache, cada uma com seu próprio mutex, mapa e heap de expiração.
+// Isso reduz a contenção do RWMutex único sob alta concorrência, já que
+// operações em chaves de shards diferentes não competem pelo mesmo lock.
+type ShardedCache struct {
+	shards []*NativeCache
+	mask   uint64
+}
+
+// NewShardedCache cria um ShardedCache com shardCount shards, cada um
+// configurado com o mesmo defaultTTL e cleanupInterval de um NativeCache
+// comum. shardCount deve ser uma potência de dois; valores inválidos caem
+// para defaultShardCount.
+func NewShardedCache(defaultTTL, cleanupInterval time.Duration, shardCount int) *ShardedCache {
+	if shardCount <= 0 || shardCount&(shardCount-1) != 0 {
+		shardCount = defaultShardCount
+	}
+
+	sc := &ShardedCache{
+		shards: make([]*NativeCache, shardCount),
+		mask:   uint64(shardCount - 1),
+	}
+
+	for i := range sc.shards {
+		sc.shards[i] = NewNativeCache(defaultTTL, cleanupInterval)
+	}
+
+	return sc
+}
+
+// shardFor escolhe o shard responsável por key usando fnv-1a, mantendo
+// chaves iguais sempre no mesmo shard.
+func (sc *ShardedCache) shardFor(key string) *NativeCache {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return sc.shards[h.Sum64()&sc.mask]
+}
+
+// Get recupera um item do shard correspondente à chave
+func (sc *ShardedCache) Get(key string) (interface{}, bool) {
+	return sc.shardFor(key).Get(key)
+}
+
+// Set adiciona ou atualiza um item no shard correspondente à chave
+func (sc *ShardedCache) Set(key string, value interface{}, ttl ...time.Duration) {
+	sc.shardFor(key).Set(key, value, ttl...)
+}
+
+// Delete remove um item do shard correspondente à chave
+func (sc *ShardedCache) Delete(key string) {
+	sc.shardFor(key).Delete(key)
+}
+
+// Has verifica se uma chave existe no shard correspondente
+func (sc *ShardedCache) Has(key string) bool {
+	return sc.shardFor(key).Has(key)
+}
+
+// Keys retorna todas as chaves de todos os shards
+func (sc *ShardedCache) Keys() []string {
+	keys := make([]string, 0, sc.Size())
+	for _, shard := range sc.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Size retorna o número total de itens somando todos os shards
+func (sc *ShardedCache) Size() int {
+	size := 0
+	for _, shard := range sc.shards {
+		size += shard.Size()
+	}
+	return size
+}
+
+// GetStats agrega as estatísticas de todos os shards
+func (sc *ShardedCache) GetStats() CacheStats {
+	var total CacheStats
+	for _, shard := range sc.shards {
+		s := shard.GetStats()
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		total.Sets += s.Sets
+		total.Deletes += s.Deletes
+		total.Cleanups += s.Cleanups
+		total.ItemsCleaned += s.ItemsCleaned
+		total.Evictions += s.Evictions
+		total.BytesInUse += s.BytesInUse
+	}
+	return total
+}
+
+// Clear remove todos os itens de todos os shards
+func (sc *ShardedCache) Clear() {
+	for _, shard := range sc.shards {
+		shard.Clear()
+	}
+}
+
+// Close encerra todos os shards e libera seus recursos
+func (sc *ShardedCache) Close() {
+	for _, shard := range sc.shards {
+		shard.Close()
+	}
+}
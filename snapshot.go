@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"time"
+)
+
+// snapshotVersion identifica o formato binário escrito por SaveSnapshot.
+// É incrementado sempre que o layout do arquivo muda, para que LoadSnapshot
+// possa recusar arquivos que não sabe interpretar.
+const snapshotVersion uint32 = 1
+
+// snapshotData é o conteúdo gravado em disco por SaveSnapshot, após o
+// cabeçalho de versão.
+type snapshotData struct {
+	Items map[string]CacheItem
+}
+
+// RegisterType registra value junto ao encoding/gob, necessário para que
+// valores guardados como interface{} em CacheItem.Value possam ser
+// codificados e decodificados em snapshots e no WAL. Chame uma vez por tipo
+// concreto usado em Set, antes do primeiro SaveSnapshot/LoadSnapshot ou de
+// usar WithWAL.
+func RegisterType(value interface{}) {
+	gob.Register(value)
+}
+
+// SaveSnapshot grava todos os itens do cache em path, num arquivo binário
+// versionado que pode ser restaurado depois com LoadSnapshot. Os tipos
+// concretos guardados nos valores devem ter sido registrados com
+// RegisterType.
+func (c *NativeCache) SaveSnapshot(path string) error {
+	c.mutex.RLock()
+	items := make(map[string]CacheItem, c.store.Len())
+	c.store.Iterate(func(key string, item CacheItem) bool {
+		items[key] = item
+		return true
+	})
+	c.mutex.RUnlock()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := binary.Write(file, binary.BigEndian, snapshotVersion); err != nil {
+		return err
+	}
+	return gob.NewEncoder(file).Encode(snapshotData{Items: items})
+}
+
+// LoadSnapshot restaura os itens salvos em path por SaveSnapshot, ignorando
+// os que já expiraram. Itens existentes no cache com as mesmas chaves são
+// sobrescritos.
+func (c *NativeCache) LoadSnapshot(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var version uint32
+	if err := binary.Read(file, binary.BigEndian, &version); err != nil {
+		return err
+	}
+	if version != snapshotVersion {
+		return fmt.Errorf("nativecache: versão de snapshot desconhecida: %d", version)
+	}
+
+	var data snapshotData
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for key, item := range data.Items {
+		if !item.ExpiresAt.After(now) {
+			continue
+		}
+		c.restoreItemLocked(key, item)
+	}
+	return nil
+}
+
+// restoreItemLocked grava item no store e reagenda sua expiração, usado ao
+// restaurar um snapshot ou reaplicar o WAL na inicialização. Deve ser
+// chamado com c.mutex travado.
+func (c *NativeCache) restoreItemLocked(key string, item CacheItem) {
+	if old, hadOld := c.store.Get(key); hadOld && c.evictionEnabled() {
+		c.stats.BytesInUse -= old.size
+	}
+
+	if c.evictionEnabled() {
+		item.size = c.sizer(item.Value)
+		c.stats.BytesInUse += item.size
+	}
+
+	c.store.Set(key, item)
+	c.scheduleExpirationLocked(key, item.ExpiresAt)
+	if c.evictionEnabled() {
+		c.touchLocked(key)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+// Store é o backend de persistência usado pelo NativeCache. O NativeCache
+// continua responsável pela expiração (heap), estatísticas e callbacks;
+// o Store só guarda e recupera os itens, o que permite trocar um mapa em
+// memória por um backend em disco ou remoto sem mexer no resto do cache.
+type Store interface {
+	Get(key string) (CacheItem, bool)
+	Set(key string, item CacheItem)
+	Delete(key string)
+	Iterate(fn func(key string, item CacheItem) bool)
+	Len() int
+	Close() error
+}
+
+// memoryStore é o backend padrão: um mapa em memória. Não tem lock próprio,
+// pois é sempre usado sob o mutex do NativeCache.
+type memoryStore struct {
+	items map[string]CacheItem
+}
+
+// newMemoryStore cria um Store em memória pronto para uso.
+func newMemoryStore() *memoryStore {
+	return &memoryStore{items: make(map[string]CacheItem)}
+}
+
+func (s *memoryStore) Get(key string) (CacheItem, bool) {
+	item, ok := s.items[key]
+	return item, ok
+}
+
+func (s *memoryStore) Set(key string, item CacheItem) {
+	s.items[key] = item
+}
+
+func (s *memoryStore) Delete(key string) {
+	delete(s.items, key)
+}
+
+func (s *memoryStore) Iterate(fn func(key string, item CacheItem) bool) {
+	for key, item := range s.items {
+		if !fn(key, item) {
+			return
+		}
+	}
+}
+
+func (s *memoryStore) Len() int {
+	return len(s.items)
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+var _ Store = (*memoryStore)(nil)
+
+// Option configura aspectos opcionais de um NativeCache no momento da
+// criação, via NewNativeCache(defaultTTL, cleanupInterval, opts...).
+type Option func(*NativeCache)
+
+// WithStore troca o backend de armazenamento padrão (em memória) por
+// store. Use para persistir itens em disco ou em um backend remoto.
+func WithStore(store Store) Option {
+	return func(c *NativeCache) {
+		c.store = store
+	}
+}
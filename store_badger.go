@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// BadgerStore persiste os itens do cache em uma BadgerDB local, sobrevivendo
+// a reinícios do processo. A expiração é delegada ao TTL nativo do Badger
+// (via SetEntry/WithTTL), além de ser reforçada pelo heap do NativeCache.
+type BadgerStore struct {
+	db *badger.DB
+}
+
+// NewBadgerStore abre (ou cria) uma BadgerDB no diretório informado.
+func NewBadgerStore(dir string) (*BadgerStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{db: db}, nil
+}
+
+func (s *BadgerStore) Get(key string) (CacheItem, bool) {
+	var item CacheItem
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		entry, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		return entry.Value(func(val []byte) error {
+			return gob.NewDecoder(bytes.NewReader(val)).Decode(&item)
+		})
+	})
+
+	return item, err == nil
+}
+
+func (s *BadgerStore) Set(key string, item CacheItem) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(item); err != nil {
+		return
+	}
+
+	_ = s.db.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry([]byte(key), buf.Bytes())
+		if ttl := time.Until(item.ExpiresAt); ttl > 0 {
+			entry = entry.WithTTL(ttl)
+		}
+		return txn.SetEntry(entry)
+	})
+}
+
+func (s *BadgerStore) Delete(key string) {
+	_ = s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (s *BadgerStore) Iterate(fn func(key string, item CacheItem) bool) {
+	_ = s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			entry := it.Item()
+			key := string(entry.Key())
+
+			var cacheItem CacheItem
+			err := entry.Value(func(val []byte) error {
+				return gob.NewDecoder(bytes.NewReader(val)).Decode(&cacheItem)
+			})
+			if err != nil {
+				continue
+			}
+
+			if !fn(key, cacheItem) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BadgerStore) Len() int {
+	count := 0
+	s.Iterate(func(string, CacheItem) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+func (s *BadgerStore) Close() error {
+	return s.db.Close()
+}
+
+var _ Store = (*BadgerStore)(nil)
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore persiste os itens do cache em um servidor Redis, serializando
+// o CacheItem com encoding/gob e usando SET ... EX para delegar a expiração
+// ao próprio Redis (reforçada, como nos demais backends, pelo heap do
+// NativeCache).
+type RedisStore struct {
+	client *redis.Client
+	ctx    context.Context
+}
+
+// NewRedisStore cria um RedisStore a partir de uma URL de conexão, no
+// formato aceito por redis.ParseURL (ex.: "redis://localhost:6379/0").
+func NewRedisStore(addr string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStore{
+		client: redis.NewClient(opts),
+		ctx:    context.Background(),
+	}, nil
+}
+
+func (s *RedisStore) Get(key string) (CacheItem, bool) {
+	var item CacheItem
+
+	data, err := s.client.Get(s.ctx, key).Bytes()
+	if err != nil {
+		return item, false
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&item); err != nil {
+		return item, false
+	}
+
+	return item, true
+}
+
+func (s *RedisStore) Set(key string, item CacheItem) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(item); err != nil {
+		return
+	}
+
+	ttl := time.Until(item.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+
+	s.client.Set(s.ctx, key, buf.Bytes(), ttl)
+}
+
+func (s *RedisStore) Delete(key string) {
+	s.client.Del(s.ctx, key)
+}
+
+func (s *RedisStore) Iterate(fn func(key string, item CacheItem) bool) {
+	iter := s.client.Scan(s.ctx, 0, "*", 0).Iterator()
+	for iter.Next(s.ctx) {
+		key := iter.Val()
+		item, ok := s.Get(key)
+		if !ok {
+			continue
+		}
+		if !fn(key, item) {
+			return
+		}
+	}
+}
+
+func (s *RedisStore) Len() int {
+	count := 0
+	s.Iterate(func(string, CacheItem) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+var _ Store = (*RedisStore)(nil)
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/williamkoller/go-native-cache/metrics"
+)
+
+// tracer é o tracer OpenTelemetry usado por GetCtx/SetCtx.
+var tracer = otel.Tracer("nativecache")
+
+// WithName dá um nome ao cache, usado para rotular suas métricas
+// Prometheus. Sem esta opção, o nome "default" é usado.
+func WithName(name string) Option {
+	return func(c *NativeCache) {
+		c.name = name
+	}
+}
+
+// WithPrometheusRegistry habilita métricas Prometheus para o cache,
+// registrando-as em reg (ou no DefaultRegisterer, se reg for nil).
+func WithPrometheusRegistry(reg *prometheus.Registry) Option {
+	return func(c *NativeCache) {
+		c.metrics = metrics.New(reg)
+	}
+}
+
+// metricsName retorna o nome usado para rotular as métricas deste cache.
+func (c *NativeCache) metricsName() string {
+	if c.name == "" {
+		return "default"
+	}
+	return c.name
+}
+
+// GetCtx é equivalente a Get, mas abre um span OpenTelemetry ("cache.Get")
+// registrando a chave e o resultado (hit/miss) como atributos do span.
+func (c *NativeCache) GetCtx(ctx context.Context, key string) (interface{}, bool) {
+	_, span := tracer.Start(ctx, "cache.Get")
+	defer span.End()
+	span.SetAttributes(attribute.String("cache.key", key))
+
+	start := time.Now()
+	value, found := c.Get(key)
+	if c.metrics != nil {
+		c.metrics.ObserveGetDuration(c.metricsName(), time.Since(start).Seconds())
+	}
+
+	span.SetAttributes(attribute.Bool("cache.hit", found))
+	return value, found
+}
+
+// SetCtx é equivalente a Set, mas abre um span OpenTelemetry ("cache.Set")
+// registrando a chave e o TTL efetivo como atributos do span.
+func (c *NativeCache) SetCtx(ctx context.Context, key string, value interface{}, ttl ...time.Duration) {
+	_, span := tracer.Start(ctx, "cache.Set")
+	defer span.End()
+
+	expiration := c.defaultTTL
+	if len(ttl) > 0 {
+		expiration = ttl[0]
+	}
+	span.SetAttributes(
+		attribute.String("cache.key", key),
+		attribute.String("cache.ttl", expiration.String()),
+	)
+
+	c.Set(key, value, ttl...)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestWithPrometheusRegistrySharedAcrossCaches cobre a regressão em que
+// duas NativeCache apontando para o mesmo *prometheus.Registry entravam em
+// pânico com "duplicate metrics collector registration attempted" --
+// exatamente o cenário que o doc comment de metrics.Collector promete
+// suportar.
+func TestWithPrometheusRegistrySharedAcrossCaches(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	a := NewNativeCache(time.Minute, time.Minute, WithName("a"), WithPrometheusRegistry(reg))
+	defer a.Close()
+	b := NewNativeCache(time.Minute, time.Minute, WithName("b"), WithPrometheusRegistry(reg))
+	defer b.Close()
+
+	a.Set("k", "v")
+	b.Set("k", "v")
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"io"
+	"os"
+	"time"
+)
+
+// WALSync controla a durabilidade das gravações feitas no write-ahead log
+// ativado por WithWAL.
+type WALSync int
+
+const (
+	// WALSyncAlways chama fsync após cada registro gravado; mais lento,
+	// mas garante que nenhum Set/Delete confirmado se perca numa queda.
+	WALSyncAlways WALSync = iota
+	// WALSyncNever confia no buffer do sistema operacional; mais rápido,
+	// porém as últimas gravações podem se perder numa queda de energia.
+	WALSyncNever
+)
+
+// walCompactThreshold é o tamanho, em bytes, a partir do qual a goroutine
+// de compactação reescreve o WAL num snapshot novo.
+const walCompactThreshold = 4 << 20 // 4 MiB
+
+type walOp byte
+
+const (
+	walOpSet walOp = iota + 1
+	walOpDelete
+)
+
+// walRecord é a unidade gravada no WAL: cada Set vira um walOpSet com o
+// valor e a expiração, cada Delete vira um walOpDelete só com a chave.
+type walRecord struct {
+	Op        walOp
+	Key       string
+	Value     interface{}
+	ExpiresAt time.Time
+}
+
+// WithWAL ativa um write-ahead log em path: cada Set/Delete passa a
+// acrescentar um registro nele antes de retornar, e NewNativeCache reaplica
+// o snapshot em path+".snapshot" seguido do restante do WAL ao iniciar,
+// recuperando o estado do cache após um restart. Os tipos concretos
+// guardados nos valores devem ter sido registrados com RegisterType. Uma
+// goroutine em segundo plano compacta o log (reescrevendo-o a partir de um
+// snapshot novo) quando ele ultrapassa alguns megabytes.
+func WithWAL(path string, syncMode WALSync) Option {
+	return func(c *NativeCache) {
+		c.walPath = path
+		c.walSync = syncMode
+	}
+}
+
+// snapshotPath retorna o caminho do snapshot associado ao WAL, usado pela
+// compactação e pela restauração na inicialização.
+func (c *NativeCache) snapshotPath() string {
+	return c.walPath + ".snapshot"
+}
+
+// restorePersisted carrega o snapshot salvo (se houver) e reaplica por cima
+// dele o restante do WAL. Chamado por NewNativeCache antes de qualquer
+// outra goroutine começar, e portanto sem concorrência a considerar.
+func (c *NativeCache) restorePersisted() error {
+	if err := c.LoadSnapshot(c.snapshotPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	file, err := os.Open(c.walPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.replayWALLocked(file)
+}
+
+// replayWALLocked lê e aplica cada registro do WAL, na ordem em que foram
+// gravados. Um registro truncado no fim do arquivo (gravação interrompida
+// por uma queda) é tratado como fim do log, não como erro. Deve ser
+// chamado com c.mutex travado.
+func (c *NativeCache) replayWALLocked(r io.Reader) error {
+	for {
+		rec, err := readWALRecord(r)
+		if err != nil {
+			return nil
+		}
+
+		switch rec.Op {
+		case walOpSet:
+			if rec.ExpiresAt.After(time.Now()) {
+				c.restoreItemLocked(rec.Key, CacheItem{Value: rec.Value, ExpiresAt: rec.ExpiresAt, CreatedAt: time.Now()})
+			} else {
+				c.store.Delete(rec.Key)
+				c.unscheduleExpirationLocked(rec.Key)
+			}
+		case walOpDelete:
+			c.store.Delete(rec.Key)
+			c.unscheduleExpirationLocked(rec.Key)
+		}
+	}
+}
+
+// readWALRecord lê um único registro length-prefixed do WAL. Retorna
+// io.EOF quando não há mais registros.
+func readWALRecord(r io.Reader) (walRecord, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return walRecord{}, err
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return walRecord{}, err
+	}
+
+	var rec walRecord
+	if err := gob.NewDecoder(bytes.NewReader(buf)).Decode(&rec); err != nil {
+		return walRecord{}, err
+	}
+	return rec, nil
+}
+
+// openWAL abre (criando se preciso) o arquivo de WAL para acréscimos e
+// inicia a goroutine de compactação em segundo plano. Chamado por
+// NewNativeCache depois de restorePersisted.
+func (c *NativeCache) openWAL() error {
+	file, err := os.OpenFile(c.walPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	c.walFile = file
+	c.walSize = info.Size()
+
+	go c.compactWALLoop()
+	return nil
+}
+
+// appendWALLocked acrescenta rec ao WAL como um registro length-prefixed.
+// Deve ser chamado com c.mutex travado, depois que a alteração já foi
+// aplicada ao store; é um no-op quando WithWAL não foi usado.
+func (c *NativeCache) appendWALLocked(rec walRecord) {
+	if c.walFile == nil {
+		return
+	}
+
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(rec); err != nil {
+		return
+	}
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(body.Len()))
+
+	n1, _ := c.walFile.Write(header[:])
+	n2, _ := c.walFile.Write(body.Bytes())
+	c.walSize += int64(n1 + n2)
+
+	if c.walSync == WALSyncAlways {
+		c.walFile.Sync()
+	}
+}
+
+// compactWALLoop dispara compactWAL periodicamente enquanto o WAL
+// ultrapassar walCompactThreshold, até o cache ser fechado.
+func (c *NativeCache) compactWALLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.mutex.RLock()
+			size := c.walSize
+			c.mutex.RUnlock()
+			if size >= walCompactThreshold {
+				c.compactWAL()
+			}
+		}
+	}
+}
+
+// compactWAL grava um snapshot com o estado atual do cache e trunca o WAL,
+// já que tudo o que ele continha agora está refletido no snapshot. Se algum
+// registro foi acrescentado ao WAL depois do snapshot, o WAL é mantido
+// intacto, pois esse registro não está no snapshot.
+func (c *NativeCache) compactWAL() error {
+	c.mutex.RLock()
+	sizeBefore := c.walSize
+	c.mutex.RUnlock()
+
+	if err := c.SaveSnapshot(c.snapshotPath()); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.walSize != sizeBefore {
+		return nil
+	}
+	if err := c.walFile.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := c.walFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	c.walSize = 0
+	return nil
+}
+
+// closeWAL compacta o WAL uma última vez e fecha o arquivo, para que o
+// próximo restart não precise reaplicar um log maior do que o necessário.
+func (c *NativeCache) closeWAL() {
+	if c.walFile == nil {
+		return
+	}
+	c.compactWAL()
+	c.walFile.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotSaveLoadRoundTrip(t *testing.T) {
+	RegisterType("")
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	cache := NewNativeCache(time.Minute, time.Minute)
+	defer cache.Close()
+	cache.Set("a", "valor-a")
+	cache.Set("b", "valor-b", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond) // b expira antes do snapshot
+
+	if err := cache.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	restored := NewNativeCache(time.Minute, time.Minute)
+	defer restored.Close()
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+
+	if v, ok := restored.Get("a"); !ok || v != "valor-a" {
+		t.Fatalf("esperava recuperar \"a\", got %v (ok=%v)", v, ok)
+	}
+	if _, ok := restored.Get("b"); ok {
+		t.Fatal("item expirado não deveria ter sido restaurado")
+	}
+}
+
+// TestEvictionAppendsWALDelete cobre a regressão em que um item removido por
+// evictIfNeededLocked continuava no WAL apenas com seu Set original e TTL
+// futuro, fazendo replayWALLocked "ressuscitá-lo" depois de um restart e
+// silenciosamente anular o limite de MaxItems/MaxBytes.
+func TestEvictionAppendsWALDelete(t *testing.T) {
+	RegisterType("")
+	walPath := filepath.Join(t.TempDir(), "cache.wal")
+
+	cache := NewNativeCache(time.Minute, time.Minute, WithMaxItems(1), WithWAL(walPath, WALSyncAlways))
+	cache.Set("first", "valor")
+	cache.Set("second", "valor") // evicta "first" (LRU, MaxItems=1)
+
+	if cache.Has("first") {
+		t.Fatal("\"first\" deveria ter sido evictado")
+	}
+
+	// Simula uma queda antes de qualquer compactação: para as goroutines e
+	// fecha o arquivo do WAL sem reescrevê-lo a partir de um snapshot, para
+	// que o replay a seguir leia exatamente o que foi gravado em disco.
+	cache.cancel()
+	cache.walFile.Close()
+
+	restored := NewNativeCache(time.Minute, time.Minute, WithMaxItems(1), WithWAL(walPath, WALSyncAlways))
+	defer restored.Close()
+
+	if restored.Has("first") {
+		t.Fatal("replay do WAL ressuscitou um item evictado por MaxItems")
+	}
+	if !restored.Has("second") {
+		t.Fatal("replay do WAL deveria ter restaurado \"second\"")
+	}
+}